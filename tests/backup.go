@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	// backupStanza is the pgBackRest stanza name used for the round-trip
+	// backup/restore test.
+	backupStanza = "test-stanza"
+
+	// backupPGData is the data directory the round-trip test backs up and
+	// restores; it matches the data_dir used by the Patroni test config.
+	backupPGData = "/var/lib/pgsql/data"
+
+	// backupRestoreTimeout bounds how long we wait for PostgreSQL to come
+	// back up after a pgbackrest restore.
+	backupRestoreTimeout = 60 * time.Second
+)
+
+// pgbackrestConfig renders a minimal pgbackrest.conf for the single-node
+// round-trip test.
+func pgbackrestConfig() string {
+	return fmt.Sprintf(`[global]
+repo1-path=/var/lib/pgbackrest
+repo1-retention-full=2
+
+[%s]
+pg1-path=%s
+pg1-port=5432
+pg1-user=postgres
+`, backupStanza, backupPGData)
+}
+
+// runBackupRoundTripTest exercises a full pgBackRest backup/restore cycle
+// against the already-running extension test container: stanza-create, a
+// full backup, an incremental backup after writing a sentinel row, then a
+// restore that should bring the sentinel row back after the table is
+// dropped. It requires postgres to have been started with archive_mode=on
+// (see TestRunner.Start), which only happens when -backup-test is passed.
+func runBackupRoundTripTest(r *TestRunner, report *Report) int {
+	fmt.Printf("  %-55s ", "pgBackRest backup/restore round-trip")
+
+	start := time.Now()
+	err := r.backupRoundTrip()
+	report.Add(TestResult{
+		Name:     "pgBackRest backup/restore round-trip",
+		Cmd:      "pgbackrest stanza-create / backup / restore",
+		Duration: time.Since(start),
+		Err:      err,
+	})
+
+	if err != nil {
+		fmt.Println("❌")
+		log.Printf("    Error: %v", err)
+		return 1
+	}
+
+	fmt.Println("✅")
+	return 0
+}
+
+func (r *TestRunner) backupRoundTrip() error {
+	if err := r.writeBackupConfig(); err != nil {
+		return fmt.Errorf("error writing pgbackrest.conf: %w", err)
+	}
+
+	if err := r.mustExec(fmt.Sprintf("pgbackrest --stanza=%s stanza-create", backupStanza)); err != nil {
+		return fmt.Errorf("error creating stanza: %w", err)
+	}
+
+	if err := r.mustExec(fmt.Sprintf("pgbackrest --stanza=%s --type=full backup", backupStanza)); err != nil {
+		return fmt.Errorf("error taking full backup: %w", err)
+	}
+
+	createSentinel := `psql -U postgres -d testdb -t -A -c "CREATE TABLE pgbackrest_sentinel (id int primary key, note text); INSERT INTO pgbackrest_sentinel VALUES (1, 'backup-sentinel');"`
+	if err := r.mustExec(createSentinel); err != nil {
+		return fmt.Errorf("error creating sentinel table: %w", err)
+	}
+
+	if err := r.mustExec(fmt.Sprintf("pgbackrest --stanza=%s --type=incr backup", backupStanza)); err != nil {
+		return fmt.Errorf("error taking incremental backup: %w", err)
+	}
+
+	dropSentinel := `psql -U postgres -d testdb -t -A -c "DROP TABLE pgbackrest_sentinel;"`
+	if err := r.mustExec(dropSentinel); err != nil {
+		return fmt.Errorf("error dropping sentinel table: %w", err)
+	}
+
+	if err := r.restoreCluster(); err != nil {
+		return err
+	}
+
+	return r.assertSentinelRestored()
+}
+
+// writeBackupConfig writes /etc/pgbackrest/pgbackrest.conf using the same
+// safe heredoc pattern used for patroni.yml, avoiding shell interpretation
+// of the config contents.
+func (r *TestRunner) writeBackupConfig() error {
+	cmd := fmt.Sprintf(`cat > /etc/pgbackrest/pgbackrest.conf <<'PGBACKREST_EOF'
+%s
+PGBACKREST_EOF`, pgbackrestConfig())
+
+	return r.mustExecShell(cmd)
+}
+
+// restoreCluster stops postgres, restores from the pgBackRest repository,
+// and waits for postgres to come back up.
+//
+// Postgres runs as PID 1 of the container (the image's entrypoint execs
+// straight into it), so a "pg_ctl stop" issued via docker exec would tear
+// down the whole container instead of just the postgres process, and the
+// restore exec right after it would fail against a container that no
+// longer exists. Stopping and restarting the container itself sidesteps
+// that: the restore runs in a short-lived sibling container that mounts
+// the stopped container's volumes via --volumes-from (only its volumes,
+// not the rest of its filesystem), then the
+// original container is started again and its entrypoint brings postgres
+// back up against the restored data directory.
+func (r *TestRunner) restoreCluster() error {
+	if err := r.cli.ContainerStop(r.ctx, r.containerID, container.StopOptions{}); err != nil {
+		return fmt.Errorf("error stopping container for restore: %w", err)
+	}
+
+	if err := r.runRestoreContainer(); err != nil {
+		return err
+	}
+
+	if err := r.cli.ContainerStart(r.ctx, r.containerID, container.StartOptions{}); err != nil {
+		return fmt.Errorf("error restarting container after restore: %w", err)
+	}
+
+	return r.waitForPostgres(backupRestoreTimeout)
+}
+
+// runRestoreContainer runs "pgbackrest --delta restore" to completion in a
+// throwaway container that mounts the stopped test container's volumes
+// via --volumes-from, then removes it.
+func (r *TestRunner) runRestoreContainer() error {
+	resp, err := r.cli.ContainerCreate(r.ctx, &container.Config{
+		Image: r.image,
+		Cmd:   []string{"pgbackrest", fmt.Sprintf("--stanza=%s", backupStanza), "--delta", "restore"},
+	}, &container.HostConfig{
+		VolumesFrom: []string{r.containerID},
+	}, nil, nil, "")
+	if err != nil {
+		return fmt.Errorf("error creating restore container: %w", err)
+	}
+	defer func() {
+		_ = r.cli.ContainerRemove(r.ctx, resp.ID, container.RemoveOptions{Force: true})
+	}()
+
+	if err := r.cli.ContainerStart(r.ctx, resp.ID, container.StartOptions{}); err != nil {
+		return fmt.Errorf("error starting restore container: %w", err)
+	}
+
+	statusCh, errCh := r.cli.ContainerWait(r.ctx, resp.ID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("error waiting for restore container: %w", err)
+	case status := <-statusCh:
+		if status.StatusCode != 0 {
+			logs, logErr := r.restoreContainerLogs(resp.ID)
+			if logErr != nil {
+				return fmt.Errorf("restore container exited %d (error reading logs: %v)", status.StatusCode, logErr)
+			}
+			return fmt.Errorf("restore container exited %d: %s", status.StatusCode, strings.TrimSpace(logs))
+		}
+	}
+
+	return nil
+}
+
+func (r *TestRunner) restoreContainerLogs(containerID string) (string, error) {
+	logs, err := r.cli.ContainerLogs(r.ctx, containerID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return "", err
+	}
+	defer logs.Close()
+
+	var outputBuf strings.Builder
+	if _, err := stdcopy.StdCopy(&outputBuf, &outputBuf, logs); err != nil {
+		return "", err
+	}
+	return outputBuf.String(), nil
+}
+
+func (r *TestRunner) assertSentinelRestored() error {
+	exitCode, output, err := r.exec(`psql -U postgres -d testdb -t -A -c "SELECT note FROM pgbackrest_sentinel WHERE id = 1;"`)
+	if err != nil {
+		return fmt.Errorf("error querying restored sentinel table: %w", err)
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("sentinel query exited %d: %s", exitCode, strings.TrimSpace(output))
+	}
+	if got := strings.TrimSpace(output); got != "backup-sentinel" {
+		return fmt.Errorf("expected restored sentinel row 'backup-sentinel', got %q", got)
+	}
+	return nil
+}
+
+// mustExec runs cmd in the test container and returns an error if it fails
+// to execute or exits non-zero.
+func (r *TestRunner) mustExec(cmd string) error {
+	exitCode, output, err := r.exec(cmd)
+	if err != nil {
+		return fmt.Errorf("error running %q: %w", cmd, err)
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("command %q exited %d: %s", cmd, exitCode, strings.TrimSpace(output))
+	}
+	return nil
+}
+
+// mustExecShell runs cmd through "sh -c" so shell operators like redirection
+// and heredocs are interpreted, unlike the word-split exec used elsewhere.
+func (r *TestRunner) mustExecShell(cmd string) error {
+	execID, err := r.cli.ContainerExecCreate(r.ctx, r.containerID, container.ExecOptions{
+		Cmd:          []string{"sh", "-c", cmd},
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating exec: %w", err)
+	}
+
+	resp, err := r.cli.ContainerExecAttach(r.ctx, execID.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return fmt.Errorf("error attaching to exec: %w", err)
+	}
+	defer resp.Close()
+
+	var outputBuf strings.Builder
+	if _, err := stdcopy.StdCopy(&outputBuf, &outputBuf, resp.Reader); err != nil {
+		return fmt.Errorf("error copying output: %w", err)
+	}
+
+	inspectResp, err := r.cli.ContainerExecInspect(r.ctx, execID.ID)
+	if err != nil {
+		return fmt.Errorf("error inspecting exec: %w", err)
+	}
+	if inspectResp.ExitCode != 0 {
+		return fmt.Errorf("command exited %d: %s", inspectResp.ExitCode, strings.TrimSpace(outputBuf.String()))
+	}
+
+	return nil
+}
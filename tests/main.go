@@ -28,6 +28,7 @@ type Test struct {
 	Cmd            string
 	ExpectedOutput func(exitCode int, output string) error
 	StandardOnly   bool // Only run on standard flavor images
+	Serial         bool // Run on the shared worker before the parallel pool (e.g. global-state tests)
 }
 
 // TestRunner manages container lifecycle and test execution
@@ -37,6 +38,7 @@ type TestRunner struct {
 	containerID string
 	image       string
 	flavor      string
+	backupTest  bool // Start postgres with archive_mode=on for the pgBackRest round-trip test
 }
 
 // DefaultEntrypointRunner tests the image with its default entrypoint
@@ -47,10 +49,15 @@ type DefaultEntrypointRunner struct {
 }
 
 func main() {
-	image, flavor := parseFlags()
+	image, flavor, reportFormats, reportFile, suitePaths, parallel, backupTest, etcdImage := parseFlags()
 
 	printHeader(image, flavor)
 
+	tests, err := buildTestSuite(suitePaths)
+	if err != nil {
+		log.Fatalf("Error building test suite: %v", err)
+	}
+
 	cli, ctx := setupDockerClient()
 	defaultRunner := &DefaultEntrypointRunner{
 		cli:   cli,
@@ -58,26 +65,47 @@ func main() {
 		image: image,
 	}
 
-	errorCount := runEntrypointTests(defaultRunner, flavor)
-	errorCount += runExtensionTests(cli, ctx, image, flavor)
+	report := &Report{}
+
+	errorCount := runEntrypointTests(defaultRunner, flavor, report)
+	errorCount += runExtensionTests(cli, ctx, image, flavor, tests, parallel, backupTest, report)
+	errorCount += runReplicationTests(cli, ctx, image, flavor, report)
+	errorCount += runPatroniHATests(cli, ctx, image, flavor, etcdImage, report)
+
+	printSummary(errorCount, report)
+
+	if err := report.Write(reportFormats, reportFile); err != nil {
+		log.Printf("Error writing report: %v", err)
+	}
 
-	printSummary(errorCount, flavor)
 	if errorCount > 0 {
 		os.Exit(1)
 	}
 }
 
-func parseFlags() (string, string) {
+func parseFlags() (string, string, []string, string, []string, int, bool, string) {
 	image := flag.String("image", "", "Docker image to test (required)")
 	flavor := flag.String("flavor", "", "Image flavor: minimal or standard (required)")
+	report := flag.String("report", "text", "Test report format(s): text, junit, tap, or a comma-separated combination")
+	reportFile := flag.String("report-file", "", "File to write the machine-readable report to (default: stdout)")
+	suite := flag.String("suite", "", "Comma-separated list of additional YAML test suite files to load alongside the embedded default suite")
+	parallel := flag.Int("parallel", 1, "Number of extension tests to run concurrently, each against its own ephemeral database")
+	backupTest := flag.Bool("backup-test", false, "Run the pgBackRest backup/restore round-trip test (standard flavor only; slower, so off by default)")
+	etcdImage := flag.String("etcd-image", defaultEtcdImage, "etcd image used as the DCS backend for the Patroni HA failover test")
 	flag.Parse()
 
 	if *image == "" || *flavor == "" {
 		fmt.Println("Usage: go run main.go -image <image> -flavor <minimal|standard>")
 		fmt.Println()
 		fmt.Println("Arguments:")
-		fmt.Println("  -image   Docker image to test (e.g., ghcr.io/pgedge/pgedge-postgres:17-spock5-standard)")
-		fmt.Println("  -flavor  Image flavor: 'minimal' or 'standard'")
+		fmt.Println("  -image        Docker image to test (e.g., ghcr.io/pgedge/pgedge-postgres:17-spock5-standard)")
+		fmt.Println("  -flavor       Image flavor: 'minimal' or 'standard'")
+		fmt.Println("  -report       Test report format(s): text, junit, tap, or a comma-separated combination (default: text)")
+		fmt.Println("  -report-file  File to write the machine-readable report to (default: stdout)")
+		fmt.Println("  -suite        Comma-separated list of additional YAML test suite files")
+		fmt.Println("  -parallel     Number of extension tests to run concurrently (default: 1)")
+		fmt.Println("  -backup-test  Run the pgBackRest backup/restore round-trip test (default: false)")
+		fmt.Printf("  -etcd-image   etcd image for the Patroni HA failover test (default: %s)\n", defaultEtcdImage)
 		os.Exit(1)
 	}
 
@@ -85,7 +113,16 @@ func parseFlags() (string, string) {
 		log.Fatalf("Invalid flavor '%s'. Must be 'minimal' or 'standard'", *flavor)
 	}
 
-	return *image, *flavor
+	if *parallel < 1 {
+		log.Fatalf("Invalid -parallel value '%d'. Must be >= 1", *parallel)
+	}
+
+	formats, err := parseReportFormats(*report)
+	if err != nil {
+		log.Fatalf("Invalid -report value: %v", err)
+	}
+
+	return *image, *flavor, formats, *reportFile, parseSuitePaths(*suite), *parallel, *backupTest, *etcdImage
 }
 
 func printHeader(image, flavor string) {
@@ -105,12 +142,15 @@ func setupDockerClient() (*client.Client, context.Context) {
 	return cli, ctx
 }
 
-func runEntrypointTests(runner *DefaultEntrypointRunner, flavor string) int {
+func runEntrypointTests(runner *DefaultEntrypointRunner, flavor string, report *Report) int {
 	errorCount := 0
 
 	// Phase 1: Test default entrypoint
 	printPhaseHeader("Phase 1: Default Entrypoint Test")
-	if err := runner.TestDefaultEntrypoint(); err != nil {
+	start := time.Now()
+	err := runner.TestDefaultEntrypoint()
+	report.Add(TestResult{Name: "Default entrypoint test", Cmd: "docker run (default entrypoint)", Duration: time.Since(start), Err: err})
+	if err != nil {
 		errorCount++
 		fmt.Printf("  Default entrypoint test                                ❌\n")
 		log.Printf("    Error: %v", err)
@@ -122,7 +162,10 @@ func runEntrypointTests(runner *DefaultEntrypointRunner, flavor string) int {
 	// Phase 2: Test Patroni entrypoint (standard only)
 	if flavor == "standard" {
 		printPhaseHeader("Phase 2: Patroni Entrypoint Test")
-		if err := runner.TestPatroniEntrypoint(); err != nil {
+		start := time.Now()
+		err := runner.TestPatroniEntrypoint()
+		report.Add(TestResult{Name: "Patroni entrypoint test", Cmd: "docker run (patroni entrypoint)", Duration: time.Since(start), Err: err})
+		if err != nil {
 			errorCount++
 			fmt.Printf("  Patroni entrypoint test                                ❌\n")
 			log.Printf("    Error: %v", err)
@@ -135,14 +178,15 @@ func runEntrypointTests(runner *DefaultEntrypointRunner, flavor string) int {
 	return errorCount
 }
 
-func runExtensionTests(cli *client.Client, ctx context.Context, image, flavor string) int {
+func runExtensionTests(cli *client.Client, ctx context.Context, image, flavor string, tests []Test, parallel int, backupTest bool, report *Report) int {
 	printPhaseHeader("Phase 3: Extension Tests")
 
 	runner := &TestRunner{
-		cli:    cli,
-		ctx:    ctx,
-		image:  image,
-		flavor: flavor,
+		cli:        cli,
+		ctx:        ctx,
+		image:      image,
+		flavor:     flavor,
+		backupTest: backupTest && flavor == "standard",
 	}
 
 	if err := runner.Start(); err != nil {
@@ -154,8 +198,13 @@ func runExtensionTests(cli *client.Client, ctx context.Context, image, flavor st
 	}
 	defer runner.Cleanup()
 
-	tests := buildTestSuite()
-	return runner.RunTests(tests)
+	errorCount := runner.RunTests(tests, parallel, report)
+
+	if runner.backupTest {
+		errorCount += runBackupRoundTripTest(runner, report)
+	}
+
+	return errorCount
 }
 
 func printPhaseHeader(title string) {
@@ -163,23 +212,14 @@ func printPhaseHeader(title string) {
 	fmt.Println()
 }
 
-func printSummary(errorCount int, flavor string) {
-	tests := buildTestSuite()
-	extensionTests := 0
-	for _, t := range tests {
-		if !t.StandardOnly || flavor == "standard" {
-			extensionTests++
-		}
-	}
-
-	testsRun := 1 + extensionTests // default entrypoint + extensions
-	if flavor == "standard" {
-		testsRun++ // patroni entrypoint
-	}
-
+// printSummary reports the total number of tests actually recorded to the
+// report across every phase (entrypoint, extensions, replication, Patroni
+// HA, backup/restore), rather than a hand-maintained count that each new
+// phase would otherwise need to remember to update.
+func printSummary(errorCount int, report *Report) {
 	fmt.Println()
 	fmt.Println("Test Summary")
-	fmt.Printf("  Tests Executed: %d\n", testsRun)
+	fmt.Printf("  Tests Executed: %d\n", len(report.Results))
 	fmt.Printf("  Errors:         %d\n", errorCount)
 	if errorCount == 0 {
 		fmt.Printf("  Status:         ✅ ALL TESTS PASSED\n")
@@ -392,6 +432,16 @@ func (r *TestRunner) Start() error {
 		"-c", "snowflake.node=1",
 	}
 
+	// Only enable WAL archiving through pgBackRest when the backup
+	// round-trip test has been requested, so the default fast suite isn't
+	// slowed down by archiving every segment.
+	if r.backupTest {
+		cmd = append(cmd,
+			"-c", "archive_mode=on",
+			"-c", fmt.Sprintf("archive_command=pgbackrest --stanza=%s archive-push %%p", backupStanza),
+		)
+	}
+
 	resp, err := r.cli.ContainerCreate(r.ctx, &container.Config{
 		Image: r.image,
 		Env: []string{
@@ -542,12 +592,17 @@ func (r *TestRunner) exec(cmd string) (int, string, error) {
 		return -1, "", fmt.Errorf("container is not running (status: %s)", inspect.State.Status)
 	}
 
-	// Parse command string safely to avoid command injection
-	// This prevents shell interpretation of the command string
-	cmdArgs := parseCommand(cmd)
+	return execInContainer(r.ctx, r.cli, r.containerID, cmd)
+}
 
-	execID, err := r.cli.ContainerExecCreate(r.ctx, r.containerID, container.ExecOptions{
-		Cmd:          cmdArgs,
+// execInContainer runs cmd inside containerID via docker exec, parsing it
+// with parseCommand to avoid shell interpretation, and returns its exit code
+// and combined stdout/stderr. It's shared by every runner (TestRunner,
+// ReplicationRunner, PatroniHARunner) that execs into containers it manages
+// directly.
+func execInContainer(ctx context.Context, cli *client.Client, containerID, cmd string) (int, string, error) {
+	execID, err := cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          parseCommand(cmd),
 		AttachStdout: true,
 		AttachStderr: true,
 	})
@@ -555,7 +610,7 @@ func (r *TestRunner) exec(cmd string) (int, string, error) {
 		return -1, "", fmt.Errorf("error creating exec: %w", err)
 	}
 
-	resp, err := r.cli.ContainerExecAttach(r.ctx, execID.ID, container.ExecAttachOptions{})
+	resp, err := cli.ContainerExecAttach(ctx, execID.ID, container.ExecAttachOptions{})
 	if err != nil {
 		return -1, "", fmt.Errorf("error attaching to exec: %w", err)
 	}
@@ -567,7 +622,7 @@ func (r *TestRunner) exec(cmd string) (int, string, error) {
 		return -1, "", fmt.Errorf("error copying output: %w", err)
 	}
 
-	inspectResp, err := r.cli.ContainerExecInspect(r.ctx, execID.ID)
+	inspectResp, err := cli.ContainerExecInspect(ctx, execID.ID)
 	if err != nil {
 		return -1, "", fmt.Errorf("error inspecting exec: %w", err)
 	}
@@ -575,225 +630,64 @@ func (r *TestRunner) exec(cmd string) (int, string, error) {
 	return inspectResp.ExitCode, outputBuf.String(), nil
 }
 
-func (r *TestRunner) RunTests(tests []Test) int {
-	errorCount := 0
-
+// RunTests runs every applicable test, routing non-serial tests to the
+// parallel pool when parallel > 1 and running the rest (plus anything
+// tagged Serial) sequentially against the shared database.
+func (r *TestRunner) RunTests(tests []Test, parallel int, report *Report) int {
+	var serialTests, parallelTests []Test
 	for _, test := range tests {
 		// Skip standard-only tests for minimal flavor
 		if test.StandardOnly && r.flavor != "standard" {
 			continue
 		}
 
-		fmt.Printf("  %-55s ", test.Name)
-
-		exitCode, output, err := r.exec(test.Cmd)
-		if err != nil {
-			errorCount++
-			fmt.Println("❌")
-			log.Printf("    Error executing command: %v", err)
-			continue
-		}
-
-		if err := test.ExpectedOutput(exitCode, output); err != nil {
-			errorCount++
-			fmt.Println("❌")
-			log.Printf("    Command: %s", test.Cmd)
-			log.Printf("    Error: %v", err)
-			log.Printf("    Output: %s", strings.TrimSpace(output))
+		if parallel <= 1 || test.Serial {
+			serialTests = append(serialTests, test)
 		} else {
-			fmt.Println("✅")
+			parallelTests = append(parallelTests, test)
 		}
 	}
 
+	errorCount := r.runSerialTests(serialTests, report)
+	errorCount += r.runParallelTests(parallelTests, parallel, report)
 	return errorCount
 }
 
-func buildTestSuite() []Test {
-	tests := []Test{}
-	tests = append(tests, getPostgreSQLTests()...)
-	tests = append(tests, getCommonExtensionTests()...)
-	tests = append(tests, getStandardOnlyTests()...)
-	return tests
-}
-
-func getPostgreSQLTests() []Test {
-	return []Test{
-		{
-			Name: "PostgreSQL accepts connections",
-			Cmd:  "psql -U postgres -d testdb -t -A -c 'SELECT 1'",
-			ExpectedOutput: func(exitCode int, output string) error {
-				if exitCode != 0 {
-					return fmt.Errorf("unexpected exit code: %d", exitCode)
-				}
-				if strings.TrimSpace(output) != "1" {
-					return fmt.Errorf("unexpected output: %s", output)
-				}
-				return nil
-			},
-		},
-		{
-			Name: "PostgreSQL version check",
-			Cmd:  "psql -U postgres -d testdb -t -A -c 'SHOW server_version'",
-			ExpectedOutput: func(exitCode int, output string) error {
-				if exitCode != 0 {
-					return fmt.Errorf("unexpected exit code: %d", exitCode)
-				}
-				if strings.TrimSpace(output) == "" {
-					return fmt.Errorf("empty version output")
-				}
-				return nil
-			},
-		},
+func (r *TestRunner) runSerialTests(tests []Test, report *Report) int {
+	errorCount := 0
+	for _, test := range tests {
+		start := time.Now()
+		exitCode, output, err := r.exec(test.Cmd)
+		if recordTestResult(test, exitCode, output, time.Since(start), err, report) {
+			errorCount++
+		}
 	}
+	return errorCount
 }
 
-func getCommonExtensionTests() []Test {
-	return []Test{
-		{
-			Name:           "Spock extension can be created",
-			Cmd:            "psql -U postgres -d testdb -t -A -c \"CREATE EXTENSION IF NOT EXISTS spock; SELECT 1;\"",
-			ExpectedOutput: expectSuccess,
-		},
-		{
-			Name: "Spock subscription table accessible",
-			Cmd:  "psql -U postgres -d testdb -t -A -c \"SELECT count(*) FROM spock.subscription;\"",
-			ExpectedOutput: func(exitCode int, output string) error {
-				if exitCode != 0 {
-					return fmt.Errorf("unexpected exit code: %d", exitCode)
-				}
-				if strings.TrimSpace(output) != "0" {
-					return fmt.Errorf("unexpected output: %s", output)
-				}
-				return nil
-			},
-		},
-		{
-			Name:           "LOLOR extension can be created",
-			Cmd:            "psql -U postgres -d testdb -t -A -c \"CREATE EXTENSION IF NOT EXISTS lolor; SELECT 1;\"",
-			ExpectedOutput: expectSuccess,
-		},
-		{
-			Name: "LOLOR lo_create works",
-			Cmd:  "psql -U postgres -d testdb -t -A -c \"SELECT lo_create(200000);\"",
-			ExpectedOutput: func(exitCode int, output string) error {
-				if exitCode != 0 {
-					return fmt.Errorf("unexpected exit code: %d", exitCode)
-				}
-				if strings.TrimSpace(output) != "200000" {
-					return fmt.Errorf("unexpected output: %s (expected 200000)", output)
-				}
-				return nil
-			},
-		},
-		{
-			Name:           "Snowflake extension can be created",
-			Cmd:            "psql -U postgres -d testdb -t -A -c \"CREATE EXTENSION IF NOT EXISTS snowflake; SELECT 1;\"",
-			ExpectedOutput: expectSuccess,
-		},
-		{
-			Name: "Snowflake ID generation works",
-			Cmd:  "psql -U postgres -d testdb -t -A -c \"SELECT snowflake.nextval() > 0;\"",
-			ExpectedOutput: func(exitCode int, output string) error {
-				if exitCode != 0 {
-					return fmt.Errorf("unexpected exit code: %d", exitCode)
-				}
-				if strings.TrimSpace(output) != "t" {
-					return fmt.Errorf("unexpected output: %s (expected 't')", output)
-				}
-				return nil
-			},
-		},
-	}
-}
+// recordTestResult prints a test's status line, logs details on failure,
+// and appends the outcome to the report. It returns true if the test
+// failed.
+func recordTestResult(test Test, exitCode int, output string, duration time.Duration, execErr error, report *Report) bool {
+	fmt.Printf("  %-55s ", test.Name)
 
-func getStandardOnlyTests() []Test {
-	return []Test{
-		{
-			Name:           "system_stats extension can be created",
-			StandardOnly:   true,
-			Cmd:            "psql -U postgres -d testdb -t -A -c \"CREATE EXTENSION IF NOT EXISTS system_stats; SELECT 1;\"",
-			ExpectedOutput: expectSuccess,
-		},
-		{
-			Name:         "system_stats pg_sys_os_info works",
-			StandardOnly: true,
-			Cmd:          "psql -U postgres -d testdb -t -A -c \"SELECT 1 FROM pg_sys_os_info();\"",
-			ExpectedOutput: func(exitCode int, output string) error {
-				if exitCode != 0 {
-					return fmt.Errorf("unexpected exit code: %d", exitCode)
-				}
-				if strings.TrimSpace(output) != "1" {
-					return fmt.Errorf("unexpected output: %s (expected 1)", output)
-				}
-				return nil
-			},
-		},
-		{
-			Name:           "pgvector extension can be created",
-			StandardOnly:   true,
-			Cmd:            "psql -U postgres -d testdb -t -A -c \"CREATE EXTENSION IF NOT EXISTS vector; SELECT 1;\"",
-			ExpectedOutput: expectSuccess,
-		},
-		{
-			Name:         "pgvector distance calculation works",
-			StandardOnly: true,
-			Cmd:          "psql -U postgres -d testdb -t -A -c \"SELECT '[1,2,3]'::vector <-> '[4,5,6]'::vector;\"",
-			ExpectedOutput: func(exitCode int, output string) error {
-				if exitCode != 0 {
-					return fmt.Errorf("unexpected exit code: %d", exitCode)
-				}
-				if !strings.HasPrefix(strings.TrimSpace(output), "5.196") {
-					return fmt.Errorf("unexpected output: %s", output)
-				}
-				return nil
-			},
-		},
-		{
-			Name:           "PostGIS extension can be created",
-			StandardOnly:   true,
-			Cmd:            "psql -U postgres -d testdb -t -A -c \"CREATE EXTENSION IF NOT EXISTS postgis; SELECT 1;\"",
-			ExpectedOutput: expectSuccess,
-		},
-		{
-			Name:         "PostGIS ST_Distance works",
-			StandardOnly: true,
-			Cmd:          "psql -U postgres -d testdb -t -A -c \"SELECT ST_Distance(ST_Point(1, 1), ST_Point(4, 5));\"",
-			ExpectedOutput: func(exitCode int, output string) error {
-				if exitCode != 0 {
-					return fmt.Errorf("unexpected exit code: %d", exitCode)
-				}
-				if strings.TrimSpace(output) != "5" {
-					return fmt.Errorf("unexpected output: %s (expected 5)", output)
-				}
-				return nil
-			},
-		},
-		{
-			Name:           "pgaudit extension can be created",
-			StandardOnly:   true,
-			Cmd:            "psql -U postgres -d testdb -t -A -c \"CREATE EXTENSION IF NOT EXISTS pgaudit; SELECT 1;\"",
-			ExpectedOutput: expectSuccess,
-		},
-		{
-			Name:         "pgBackRest is installed",
-			StandardOnly: true,
-			Cmd:          "pgbackrest version",
-			ExpectedOutput: func(exitCode int, output string) error {
-				if exitCode != 0 {
-					return fmt.Errorf("unexpected exit code: %d", exitCode)
-				}
-				if !strings.Contains(output, "pgBackRest") {
-					return fmt.Errorf("unexpected output: %s", output)
-				}
-				return nil
-			},
-		},
+	if execErr != nil {
+		fmt.Println("❌")
+		log.Printf("    Error executing command: %v", execErr)
+		report.Add(TestResult{Name: test.Name, Cmd: test.Cmd, ExitCode: exitCode, Duration: duration, Output: output, Err: execErr})
+		return true
 	}
-}
 
-func expectSuccess(exitCode int, output string) error {
-	if exitCode != 0 {
-		return fmt.Errorf("unexpected exit code: %d", exitCode)
+	if err := test.ExpectedOutput(exitCode, output); err != nil {
+		fmt.Println("❌")
+		log.Printf("    Command: %s", test.Cmd)
+		log.Printf("    Error: %v", err)
+		log.Printf("    Output: %s", strings.TrimSpace(output))
+		report.Add(TestResult{Name: test.Name, Cmd: test.Cmd, ExitCode: exitCode, Duration: duration, Output: output, Err: err})
+		return true
 	}
-	return nil
+
+	fmt.Println("✅")
+	report.Add(TestResult{Name: test.Name, Cmd: test.Cmd, ExitCode: exitCode, Duration: duration, Output: output})
+	return false
 }
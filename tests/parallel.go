@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// parallelTestResult carries a completed worker execution back to the
+// collector goroutine for printing and reporting.
+type parallelTestResult struct {
+	test     Test
+	exitCode int
+	output   string
+	err      error
+	duration time.Duration
+}
+
+// runParallelTests fans tests out across `workers` goroutines, each backed
+// by its own ephemeral database, and records results as they complete.
+func (r *TestRunner) runParallelTests(tests []Test, workers int, report *Report) int {
+	if len(tests) == 0 {
+		return 0
+	}
+
+	jobs := make(chan Test)
+	results := make(chan parallelTestResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			r.runTestWorker(workerID, jobs, results)
+		}(i)
+	}
+
+	go func() {
+		for _, test := range tests {
+			jobs <- test
+		}
+		close(jobs)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	errorCount := 0
+	for res := range results {
+		if recordTestResult(res.test, res.exitCode, res.output, res.duration, res.err, report) {
+			errorCount++
+		}
+	}
+	return errorCount
+}
+
+// runTestWorker creates an ephemeral database up front, runs every job
+// routed to it against that database, then drops it on the way out.
+func (r *TestRunner) runTestWorker(workerID int, jobs <-chan Test, results chan<- parallelTestResult) {
+	dbName := fmt.Sprintf("test_%d_%x", workerID, rand.Int63())
+
+	if err := r.createTestDatabase(dbName); err != nil {
+		setupErr := fmt.Errorf("worker %d: error creating ephemeral database: %w", workerID, err)
+		for test := range jobs {
+			results <- parallelTestResult{test: test, exitCode: -1, err: setupErr}
+		}
+		return
+	}
+	defer r.dropTestDatabase(dbName)
+
+	for test := range jobs {
+		routedCmd, err := routeToDatabase(test.Cmd, dbName)
+		if err != nil {
+			results <- parallelTestResult{test: test, exitCode: -1, err: err}
+			continue
+		}
+
+		start := time.Now()
+		exitCode, output, err := r.exec(routedCmd)
+		results <- parallelTestResult{test: test, exitCode: exitCode, output: output, err: err, duration: time.Since(start)}
+	}
+}
+
+func (r *TestRunner) createTestDatabase(name string) error {
+	exitCode, output, err := r.exec(fmt.Sprintf("psql -U postgres -d postgres -t -A -c \"CREATE DATABASE %s\"", name))
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("createdb exited %d: %s", exitCode, strings.TrimSpace(output))
+	}
+	return nil
+}
+
+func (r *TestRunner) dropTestDatabase(name string) {
+	exitCode, output, err := r.exec(fmt.Sprintf("psql -U postgres -d postgres -t -A -c \"DROP DATABASE IF EXISTS %s\"", name))
+	if err != nil {
+		log.Printf("Error dropping ephemeral database %s: %v", name, err)
+		return
+	}
+	if exitCode != 0 {
+		log.Printf("Error dropping ephemeral database %s: exited %d: %s", name, exitCode, strings.TrimSpace(output))
+	}
+}
+
+// routeToDatabase rewrites a test's "-d testdb" target to the worker's own
+// ephemeral database so parallel workers don't contend on shared state. It
+// errors out rather than silently running against the shared testdb if the
+// command doesn't contain that marker, since a test added without it (e.g.
+// via a YAML suite spelling the flag differently) would otherwise defeat
+// per-worker isolation with no indication anything was wrong.
+func routeToDatabase(cmd, dbName string) (string, error) {
+	const marker = "-d testdb"
+	if !strings.Contains(cmd, marker) {
+		return "", fmt.Errorf("test command %q does not contain %q; non-serial tests must target testdb via %q so they can be routed to a per-worker database, or be tagged serial: true", cmd, marker, marker)
+	}
+	return strings.Replace(cmd, marker, fmt.Sprintf("-d %s", dbName), 1), nil
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+const (
+	// defaultEtcdImage is the DCS backend used to coordinate the Patroni
+	// cluster when -etcd-image isn't passed.
+	defaultEtcdImage = "quay.io/coreos/etcd:v3.5.13"
+
+	// patroniHANodeCount is the number of pgEdge containers joined to the
+	// Patroni cluster under test.
+	patroniHANodeCount = 3
+
+	// patroniConvergenceTimeout bounds how long we wait for the cluster to
+	// report one leader and the rest as running replicas.
+	patroniConvergenceTimeout = 120 * time.Second
+
+	// patroniFailoverTimeout bounds how long we wait for a new leader to be
+	// elected after the old one is killed.
+	patroniFailoverTimeout = 60 * time.Second
+)
+
+// patroniHANode is a single pgEdge container participating in the Patroni
+// cluster under test.
+type patroniHANode struct {
+	name        string
+	containerID string
+}
+
+// PatroniHARunner stands up a real etcd-backed Patroni cluster to exercise
+// leader election and failover, rather than just checking that a lone node's
+// REST API responds.
+type PatroniHARunner struct {
+	cli         *client.Client
+	ctx         context.Context
+	image       string
+	etcdImage   string
+	networkID   string
+	etcdName    string
+	etcdID      string
+	nodes       []patroniHANode
+	restAPIPort string
+}
+
+// patroniClusterMember mirrors the fields we care about from Patroni's
+// GET /cluster REST API response.
+type patroniClusterMember struct {
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+	State string `json:"state"`
+}
+
+type patroniClusterResponse struct {
+	Members []patroniClusterMember `json:"members"`
+}
+
+// runPatroniHATests builds a 3-node Patroni cluster wired to a real etcd
+// instance, kills the elected leader, and asserts a new leader is elected
+// and promoted within a deadline. It is standard-flavor only, matching
+// TestPatroniEntrypoint.
+func runPatroniHATests(cli *client.Client, ctx context.Context, image, flavor, etcdImage string, report *Report) int {
+	if flavor != "standard" {
+		return 0
+	}
+
+	printPhaseHeader("Phase 5: Patroni HA Failover Test")
+
+	r := &PatroniHARunner{cli: cli, ctx: ctx, image: image, etcdImage: etcdImage}
+	defer r.Cleanup()
+
+	start := time.Now()
+	err := r.Setup()
+	if err == nil {
+		err = r.RunFailover()
+	}
+	report.Add(TestResult{Name: "Patroni HA failover test", Cmd: "patroni REST API /cluster (leader kill + re-election)", Duration: time.Since(start), Err: err})
+
+	if err != nil {
+		fmt.Printf("  Patroni HA failover test                               ❌\n")
+		log.Printf("    Error: %v", err)
+		return 1
+	}
+
+	fmt.Printf("  Patroni HA failover test                               ✅\n")
+	fmt.Println()
+	return 0
+}
+
+// Setup creates the shared network, starts the etcd sidecar, then starts
+// patroniHANodeCount pgEdge containers and waits for the cluster to
+// converge on exactly one leader and the rest running.
+func (r *PatroniHARunner) Setup() error {
+	fmt.Println("  Creating Patroni HA network...")
+	netName := fmt.Sprintf("pgedge-patroni-ha-%d", rand.Intn(1_000_000))
+	resp, err := r.cli.NetworkCreate(r.ctx, netName, network.CreateOptions{Driver: "bridge"})
+	if err != nil {
+		return fmt.Errorf("error creating network: %w", err)
+	}
+	r.networkID = resp.ID
+
+	fmt.Println("  Starting etcd...")
+	if err := r.startEtcd(); err != nil {
+		return fmt.Errorf("error starting etcd: %w", err)
+	}
+
+	for i := 1; i <= patroniHANodeCount; i++ {
+		name := fmt.Sprintf("pgedge-patroni%d-%d", i, rand.Intn(1_000_000))
+		fmt.Printf("  Starting Patroni node %d (%s)...\n", i, name)
+
+		containerID, err := r.startNode(name)
+		if containerID != "" {
+			// Record the node for cleanup as soon as it exists, even if
+			// starting it below failed, so Cleanup doesn't leak it.
+			r.nodes = append(r.nodes, patroniHANode{name: name, containerID: containerID})
+		}
+		if err != nil {
+			return fmt.Errorf("error starting node %d: %w", i, err)
+		}
+	}
+
+	fmt.Println("  Waiting for cluster to converge...")
+	return r.waitForConvergence()
+}
+
+func (r *PatroniHARunner) startEtcd() error {
+	r.etcdName = fmt.Sprintf("pgedge-etcd-%d", rand.Intn(1_000_000))
+
+	cmd := []string{
+		"etcd",
+		"--name", r.etcdName,
+		"--advertise-client-urls", fmt.Sprintf("http://%s:2379", r.etcdName),
+		"--listen-client-urls", "http://0.0.0.0:2379",
+	}
+
+	resp, err := r.cli.ContainerCreate(r.ctx, &container.Config{
+		Image:    r.etcdImage,
+		Hostname: r.etcdName,
+		Cmd:      cmd,
+	}, &container.HostConfig{}, &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			r.networkID: {Aliases: []string{r.etcdName}},
+		},
+	}, nil, r.etcdName)
+	if err != nil {
+		return err
+	}
+	r.etcdID = resp.ID
+
+	if err := r.cli.ContainerStart(r.ctx, r.etcdID, container.StartOptions{}); err != nil {
+		return err
+	}
+
+	// Give etcd a moment to start listening before Patroni tries to connect.
+	time.Sleep(3 * time.Second)
+	return nil
+}
+
+func (r *PatroniHARunner) startNode(name string) (string, error) {
+	config := fmt.Sprintf(`scope: pgedge-ha-test
+name: %s
+
+restapi:
+  listen: 0.0.0.0:8008
+  connect_address: %s:8008
+
+etcd3:
+  hosts: %s:2379
+
+bootstrap:
+  dcs:
+    ttl: 30
+    loop_wait: 10
+    retry_timeout: 10
+    maximum_lag_on_failover: 1048576
+  initdb:
+    - encoding: UTF8
+    - data-checksums
+
+postgresql:
+  listen: 0.0.0.0:5432
+  connect_address: %s:5432
+  data_dir: /var/lib/pgsql/data
+  authentication:
+    superuser:
+      username: postgres
+      password: testpassword
+    replication:
+      username: replicator
+      password: testpassword
+`, name, name, r.etcdName, name)
+
+	cmd := fmt.Sprintf(`cat > /tmp/patroni.yml <<'PATRONI_EOF'
+%s
+PATRONI_EOF
+patroni /tmp/patroni.yml`, config)
+
+	resp, err := r.cli.ContainerCreate(r.ctx, &container.Config{
+		Image:    r.image,
+		Hostname: name,
+		Env: []string{
+			"PATRONI_SCOPE=pgedge-ha-test",
+			fmt.Sprintf("PATRONI_NAME=%s", name),
+		},
+		Cmd: []string{"sh", "-c", cmd},
+	}, &container.HostConfig{}, &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			r.networkID: {Aliases: []string{name}},
+		},
+	}, nil, name)
+	if err != nil {
+		return "", err
+	}
+
+	if err := r.cli.ContainerStart(r.ctx, resp.ID, container.StartOptions{}); err != nil {
+		// Return the container ID even on failure so the caller can still
+		// record it for cleanup; the container exists even though it never
+		// started running.
+		return resp.ID, err
+	}
+
+	return resp.ID, nil
+}
+
+// waitForConvergence polls /cluster on the first node's REST API until
+// exactly one member reports role "leader" and the remaining members
+// report state "running".
+func (r *PatroniHARunner) waitForConvergence() error {
+	deadline := time.Now().Add(patroniConvergenceTimeout)
+	for time.Now().Before(deadline) {
+		cluster, err := r.fetchCluster(r.nodes[0])
+		if err == nil {
+			leaders, running := countRolesAndStates(cluster)
+			if leaders == 1 && running == len(r.nodes)-1 {
+				fmt.Println("  Cluster converged with one leader and running replicas!")
+				return nil
+			}
+		}
+		time.Sleep(2 * time.Second)
+	}
+	return fmt.Errorf("timeout waiting for Patroni cluster to converge")
+}
+
+func countRolesAndStates(cluster *patroniClusterResponse) (leaders, running int) {
+	for _, m := range cluster.Members {
+		if m.Role == "leader" {
+			leaders++
+		} else if m.State == "running" {
+			running++
+		}
+	}
+	return leaders, running
+}
+
+// fetchCluster queries a node's REST API for the Patroni cluster topology
+// by running curl inside the node's own container against
+// 127.0.0.1:8008/cluster.
+func (r *PatroniHARunner) fetchCluster(node patroniHANode) (*patroniClusterResponse, error) {
+	exitCode, output, err := r.exec(node.containerID, "curl -sf http://127.0.0.1:8008/cluster")
+	if err != nil {
+		return nil, err
+	}
+	if exitCode != 0 {
+		return nil, fmt.Errorf("curl exited %d", exitCode)
+	}
+
+	var cluster patroniClusterResponse
+	if err := json.Unmarshal([]byte(output), &cluster); err != nil {
+		return nil, fmt.Errorf("error parsing cluster response: %w", err)
+	}
+	return &cluster, nil
+}
+
+// RunFailover stops the current leader and asserts that a new leader is
+// elected within patroniFailoverTimeout and that it has been promoted to
+// read-write.
+func (r *PatroniHARunner) RunFailover() error {
+	leader, err := r.findLeader()
+	if err != nil {
+		return fmt.Errorf("error finding current leader: %w", err)
+	}
+	fmt.Printf("  Current leader is %s, killing it...\n", leader.name)
+
+	if err := r.cli.ContainerKill(r.ctx, leader.containerID, "SIGKILL"); err != nil {
+		return fmt.Errorf("error killing leader: %w", err)
+	}
+
+	fmt.Println("  Waiting for new leader to be elected...")
+	newLeader, err := r.waitForNewLeader(leader.name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  New leader elected: %s\n", newLeader.name)
+
+	return r.assertPromoted(newLeader)
+}
+
+func (r *PatroniHARunner) findLeader() (patroniHANode, error) {
+	for _, node := range r.nodes {
+		cluster, err := r.fetchCluster(node)
+		if err != nil {
+			continue
+		}
+		for _, m := range cluster.Members {
+			if m.Role == "leader" {
+				for _, n := range r.nodes {
+					if n.name == m.Name {
+						return n, nil
+					}
+				}
+			}
+		}
+	}
+	return patroniHANode{}, fmt.Errorf("no leader found among cluster members")
+}
+
+func (r *PatroniHARunner) waitForNewLeader(oldLeaderName string) (patroniHANode, error) {
+	deadline := time.Now().Add(patroniFailoverTimeout)
+	for time.Now().Before(deadline) {
+		for _, node := range r.nodes {
+			if node.name == oldLeaderName {
+				continue
+			}
+			cluster, err := r.fetchCluster(node)
+			if err != nil {
+				continue
+			}
+			for _, m := range cluster.Members {
+				if m.Role == "leader" && m.Name != oldLeaderName {
+					for _, n := range r.nodes {
+						if n.name == m.Name {
+							return n, nil
+						}
+					}
+				}
+			}
+		}
+		time.Sleep(2 * time.Second)
+	}
+	return patroniHANode{}, fmt.Errorf("timeout waiting for new leader election")
+}
+
+func (r *PatroniHARunner) assertPromoted(node patroniHANode) error {
+	exitCode, output, err := r.exec(node.containerID, "psql -U postgres -t -A -c \"SELECT pg_is_in_recovery()\"")
+	if err != nil {
+		return fmt.Errorf("error checking recovery status: %w", err)
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("pg_is_in_recovery check exited %d", exitCode)
+	}
+	if strings.TrimSpace(output) != "f" {
+		return fmt.Errorf("expected promoted node to report pg_is_in_recovery() = false, got %q", strings.TrimSpace(output))
+	}
+	return nil
+}
+
+func (r *PatroniHARunner) exec(containerID, cmd string) (int, string, error) {
+	return execInContainer(r.ctx, r.cli, containerID, cmd)
+}
+
+// Cleanup stops and removes every Patroni node, the etcd sidecar, and the
+// shared network, even if setup or failover failed partway through.
+func (r *PatroniHARunner) Cleanup() {
+	for _, node := range r.nodes {
+		_ = r.cli.ContainerStop(r.ctx, node.containerID, container.StopOptions{})
+		if err := r.cli.ContainerRemove(r.ctx, node.containerID, container.RemoveOptions{}); err != nil {
+			log.Printf("Error removing Patroni node %s: %v", node.name, err)
+		}
+	}
+
+	if r.etcdID != "" {
+		_ = r.cli.ContainerStop(r.ctx, r.etcdID, container.StopOptions{})
+		if err := r.cli.ContainerRemove(r.ctx, r.etcdID, container.RemoveOptions{}); err != nil {
+			log.Printf("Error removing etcd: %v", err)
+		}
+	}
+
+	if r.networkID != "" {
+		if err := r.cli.NetworkRemove(r.ctx, r.networkID); err != nil {
+			log.Printf("Error removing Patroni HA network: %v", err)
+		}
+	}
+}
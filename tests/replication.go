@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+const (
+	// replicationNodeCount is the number of containers started to form the
+	// Spock provider/subscriber mesh.
+	replicationNodeCount = 2
+
+	// replicationConvergenceTimeout bounds how long we wait for a row written
+	// on the provider to show up on the subscriber.
+	replicationConvergenceTimeout = 60 * time.Second
+
+	// replicationPollInterval is how often we re-check the subscriber while
+	// waiting for convergence.
+	replicationPollInterval = 1 * time.Second
+)
+
+// standardOnlyReplication is true if exercising spock-to-spock replication
+// requires an extension that is only shipped in the standard flavor. Spock
+// and snowflake are present on every flavor today, but this stays in one
+// place so a future dependency on a standard-only extension only needs to
+// flip this switch.
+const standardOnlyReplication = false
+
+// replicationNode is a single pgEdge container participating in the
+// replication mesh.
+type replicationNode struct {
+	name        string
+	containerID string
+	nodeID      int
+}
+
+// ReplicationRunner manages a small Docker network of pgEdge containers used
+// to exercise real Spock logical replication end-to-end, rather than just
+// confirming the extension loads.
+type ReplicationRunner struct {
+	cli       *client.Client
+	ctx       context.Context
+	image     string
+	flavor    string
+	networkID string
+	nodes     []replicationNode
+}
+
+// runReplicationTests wires up a Spock provider/subscriber pair on
+// a dedicated Docker network and asserts that rows written on the provider
+// arrive on the subscriber. It cleans up all containers and the network
+// regardless of outcome.
+func runReplicationTests(cli *client.Client, ctx context.Context, image, flavor string, report *Report) int {
+	printPhaseHeader("Phase 4: Spock Replication Test")
+
+	if standardOnlyReplication && flavor != "standard" {
+		fmt.Println("  Skipping: spock-to-spock replication requires standard-only extensions")
+		fmt.Println()
+		return 0
+	}
+
+	r := &ReplicationRunner{
+		cli:    cli,
+		ctx:    ctx,
+		image:  image,
+		flavor: flavor,
+	}
+	defer r.Cleanup()
+
+	start := time.Now()
+	err := r.Setup()
+	if err == nil {
+		err = r.RunReplication()
+	}
+	report.Add(TestResult{Name: "Spock replication test", Cmd: "spock.sub_create (provider -> subscriber)", Duration: time.Since(start), Err: err})
+
+	if err != nil {
+		fmt.Printf("  Spock replication test                                 ❌\n")
+		log.Printf("    Error: %v", err)
+		return 1
+	}
+
+	fmt.Printf("  Spock replication test                                 ✅\n")
+	fmt.Println()
+	return 0
+}
+
+// Setup creates the Docker network and starts replicationNodeCount
+// containers, each with a distinct snowflake.node value.
+func (r *ReplicationRunner) Setup() error {
+	fmt.Println("  Creating replication network...")
+
+	netName := fmt.Sprintf("pgedge-repl-%d", rand.Intn(1_000_000))
+	resp, err := r.cli.NetworkCreate(r.ctx, netName, network.CreateOptions{Driver: "bridge"})
+	if err != nil {
+		return fmt.Errorf("error creating network: %w", err)
+	}
+	r.networkID = resp.ID
+
+	for i := 1; i <= replicationNodeCount; i++ {
+		name := fmt.Sprintf("pgedge-repl-node%d-%d", i, rand.Intn(1_000_000))
+		fmt.Printf("  Starting node %d (%s)...\n", i, name)
+
+		containerID, err := r.startNode(name, i)
+		if containerID != "" {
+			// Record the node for cleanup as soon as it exists, even if
+			// starting it below failed, so Cleanup doesn't leak it.
+			r.nodes = append(r.nodes, replicationNode{name: name, containerID: containerID, nodeID: i})
+		}
+		if err != nil {
+			return fmt.Errorf("error starting node %d: %w", i, err)
+		}
+
+		if err := r.waitForNode(r.nodes[len(r.nodes)-1]); err != nil {
+			return fmt.Errorf("node %d failed to become ready: %w", i, err)
+		}
+	}
+
+	fmt.Println("  All nodes ready")
+	return nil
+}
+
+func (r *ReplicationRunner) startNode(name string, nodeID int) (string, error) {
+	sharedLibs := "spock,snowflake"
+	if r.flavor == "standard" {
+		sharedLibs = "spock,snowflake,pgaudit"
+	}
+
+	cmd := []string{
+		"postgres",
+		"-c", fmt.Sprintf("shared_preload_libraries=%s", sharedLibs),
+		"-c", "wal_level=logical",
+		"-c", "track_commit_timestamp=on",
+		"-c", "max_replication_slots=10",
+		"-c", "max_wal_senders=10",
+		"-c", fmt.Sprintf("snowflake.node=%d", nodeID),
+	}
+
+	resp, err := r.cli.ContainerCreate(r.ctx, &container.Config{
+		Image:    r.image,
+		Hostname: name,
+		Env: []string{
+			"POSTGRES_PASSWORD=testpassword",
+			"POSTGRES_USER=postgres",
+			"POSTGRES_DB=testdb",
+		},
+		Cmd: cmd,
+	}, &container.HostConfig{}, &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			r.networkID: {Aliases: []string{name}},
+		},
+	}, nil, name)
+	if err != nil {
+		return "", fmt.Errorf("error creating container: %w", err)
+	}
+
+	if err := r.cli.ContainerStart(r.ctx, resp.ID, container.StartOptions{}); err != nil {
+		// Return the container ID even on failure so the caller can still
+		// record it for cleanup; the container exists even though it never
+		// started running.
+		return resp.ID, fmt.Errorf("error starting container: %w", err)
+	}
+
+	return resp.ID, nil
+}
+
+func (r *ReplicationRunner) waitForNode(node replicationNode) error {
+	deadline := time.Now().Add(60 * time.Second)
+	for time.Now().Before(deadline) {
+		exitCode, _, err := r.exec(node.containerID, "pg_isready -U postgres")
+		if err == nil && exitCode == 0 {
+			exitCode, _, err := r.exec(node.containerID, "psql -U postgres -d testdb -t -A -c 'SELECT 1'")
+			if err == nil && exitCode == 0 {
+				time.Sleep(postgresStabilizationPeriod)
+				return nil
+			}
+		}
+		time.Sleep(1 * time.Second)
+	}
+	return fmt.Errorf("timeout waiting for node %s to become ready", node.name)
+}
+
+// RunReplication configures a spock.node/spock.subscription pair between the
+// first two nodes, writes rows on the provider, and waits for them to show
+// up on the subscriber.
+func (r *ReplicationRunner) RunReplication() error {
+	if len(r.nodes) < 2 {
+		return fmt.Errorf("need at least 2 nodes to test replication, got %d", len(r.nodes))
+	}
+	provider := r.nodes[0]
+	subscriber := r.nodes[1]
+
+	fmt.Println("  Creating spock nodes...")
+	if err := r.mustExec(provider, "psql -U postgres -d testdb -t -A -c \"CREATE EXTENSION IF NOT EXISTS spock;\""); err != nil {
+		return err
+	}
+	if err := r.mustExec(subscriber, "psql -U postgres -d testdb -t -A -c \"CREATE EXTENSION IF NOT EXISTS spock;\""); err != nil {
+		return err
+	}
+
+	providerDSN := fmt.Sprintf("host=%s port=5432 dbname=testdb user=postgres password=testpassword", provider.name)
+	subscriberDSN := fmt.Sprintf("host=%s port=5432 dbname=testdb user=postgres password=testpassword", subscriber.name)
+
+	createProviderNode := fmt.Sprintf(
+		"psql -U postgres -d testdb -t -A -c \"SELECT spock.node_create('%s', '%s');\"",
+		provider.name, providerDSN)
+	if err := r.mustExec(provider, createProviderNode); err != nil {
+		return err
+	}
+
+	createSubscriberNode := fmt.Sprintf(
+		"psql -U postgres -d testdb -t -A -c \"SELECT spock.node_create('%s', '%s');\"",
+		subscriber.name, subscriberDSN)
+	if err := r.mustExec(subscriber, createSubscriberNode); err != nil {
+		return err
+	}
+
+	fmt.Println("  Creating sentinel table...")
+	createTable := "psql -U postgres -d testdb -t -A -c \"CREATE TABLE IF NOT EXISTS spock_repl_check (id int primary key, note text);\""
+	if err := r.mustExec(provider, createTable); err != nil {
+		return err
+	}
+	if err := r.mustExec(subscriber, createTable); err != nil {
+		return err
+	}
+
+	addRepset := "psql -U postgres -d testdb -t -A -c \"SELECT spock.repset_add_table('default', 'spock_repl_check');\""
+	if err := r.mustExec(provider, addRepset); err != nil {
+		return err
+	}
+
+	fmt.Println("  Creating subscription...")
+	createSub := fmt.Sprintf(
+		"psql -U postgres -d testdb -t -A -c \"SELECT spock.sub_create('sub_%s_%s', '%s');\"",
+		subscriber.name, provider.name, providerDSN)
+	if err := r.mustExec(subscriber, createSub); err != nil {
+		return err
+	}
+
+	fmt.Println("  Writing rows on provider...")
+	insert := "psql -U postgres -d testdb -t -A -c \"INSERT INTO spock_repl_check VALUES (1, 'replicated-row');\""
+	if err := r.mustExec(provider, insert); err != nil {
+		return err
+	}
+
+	fmt.Println("  Waiting for row to arrive on subscriber...")
+	return r.waitForRow(subscriber)
+}
+
+func (r *ReplicationRunner) waitForRow(subscriber replicationNode) error {
+	deadline := time.Now().Add(replicationConvergenceTimeout)
+	for time.Now().Before(deadline) {
+		exitCode, output, err := r.exec(subscriber.containerID,
+			"psql -U postgres -d testdb -t -A -c \"SELECT note FROM spock_repl_check WHERE id = 1;\"")
+		if err == nil && exitCode == 0 && strings.TrimSpace(output) == "replicated-row" {
+			fmt.Println("  Row replicated successfully!")
+			return nil
+		}
+		time.Sleep(replicationPollInterval)
+	}
+	return fmt.Errorf("timeout waiting for row to replicate to subscriber")
+}
+
+func (r *ReplicationRunner) mustExec(node replicationNode, cmd string) error {
+	exitCode, output, err := r.exec(node.containerID, cmd)
+	if err != nil {
+		return fmt.Errorf("error running %q on %s: %w", cmd, node.name, err)
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("command %q on %s exited %d: %s", cmd, node.name, exitCode, strings.TrimSpace(output))
+	}
+	return nil
+}
+
+func (r *ReplicationRunner) exec(containerID, cmd string) (int, string, error) {
+	return execInContainer(r.ctx, r.cli, containerID, cmd)
+}
+
+// Cleanup stops and removes every node container plus the replication
+// network, even if setup or replication failed partway through.
+func (r *ReplicationRunner) Cleanup() {
+	for _, node := range r.nodes {
+		_ = r.cli.ContainerStop(r.ctx, node.containerID, container.StopOptions{})
+		if err := r.cli.ContainerRemove(r.ctx, node.containerID, container.RemoveOptions{}); err != nil {
+			log.Printf("Error removing replication node %s: %v", node.name, err)
+		}
+	}
+
+	if r.networkID != "" {
+		if err := r.cli.NetworkRemove(r.ctx, r.networkID); err != nil {
+			log.Printf("Error removing replication network: %v", err)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TestResult captures the outcome of a single test or phase check so it can
+// be rendered as JUnit XML or TAP13 in addition to the default pretty
+// output.
+type TestResult struct {
+	Name     string
+	Cmd      string
+	ExitCode int
+	Duration time.Duration
+	Output   string
+	Err      error
+}
+
+// Passed reports whether the test result represents a success.
+func (t TestResult) Passed() bool {
+	return t.Err == nil
+}
+
+// Report accumulates TestResults across every phase of a run so they can be
+// rendered in one or more machine-readable formats alongside the default
+// text output.
+type Report struct {
+	Results []TestResult
+}
+
+// Add appends a result to the report.
+func (r *Report) Add(res TestResult) {
+	r.Results = append(r.Results, res)
+}
+
+// supportedReportFormats are the formats accepted by the -report flag.
+var supportedReportFormats = map[string]bool{
+	"text":  true,
+	"junit": true,
+	"tap":   true,
+}
+
+// parseReportFormats splits a comma-separated -report value and validates
+// each entry, defaulting to "text" when nothing is given.
+func parseReportFormats(flagValue string) ([]string, error) {
+	var formats []string
+	for _, f := range strings.Split(flagValue, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !supportedReportFormats[f] {
+			return nil, fmt.Errorf("unsupported report format %q (supported: text, junit, tap)", f)
+		}
+		formats = append(formats, f)
+	}
+	if len(formats) == 0 {
+		formats = []string{"text"}
+	}
+	return formats, nil
+}
+
+// Write renders the report in every requested machine-readable format.
+// "text" is a no-op here since the pretty output is already printed as
+// tests run; it exists so "-report=text,junit" works without error. JUnit
+// and TAP are written to reportFile if set, otherwise to stdout. When
+// reportFile is set and more than one machine format is requested, the
+// format name is inserted before the file extension so outputs don't
+// clobber each other (e.g. "results.xml" -> "results-junit.xml").
+func (r *Report) Write(formats []string, reportFile string) error {
+	machineFormats := 0
+	for _, f := range formats {
+		if f != "text" {
+			machineFormats++
+		}
+	}
+
+	for _, format := range formats {
+		var render func(io.Writer) error
+		switch format {
+		case "text":
+			continue
+		case "junit":
+			render = r.writeJUnit
+		case "tap":
+			render = r.writeTAP
+		default:
+			continue
+		}
+
+		path := reportFile
+		if path != "" && machineFormats > 1 {
+			path = insertSuffix(path, format)
+		}
+
+		if err := writeReportTo(path, render); err != nil {
+			return fmt.Errorf("error writing %s report: %w", format, err)
+		}
+	}
+	return nil
+}
+
+func insertSuffix(path, suffix string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	return fmt.Sprintf("%s-%s%s", base, suffix, ext)
+}
+
+func writeReportTo(path string, render func(io.Writer) error) error {
+	if path == "" {
+		return render(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating report file: %w", err)
+	}
+	defer f.Close()
+	return render(f)
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Content string `xml:",chardata"`
+}
+
+type junitTestCase struct {
+	XMLName   xml.Name      `xml:"testcase"`
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// writeJUnit renders the report as a single JUnit XML testsuite.
+func (r *Report) writeJUnit(w io.Writer) error {
+	suite := junitTestSuite{Name: "pgedge-postgres-image-tests"}
+
+	var total time.Duration
+	for _, res := range r.Results {
+		total += res.Duration
+
+		tc := junitTestCase{
+			Name:      res.Name,
+			ClassName: "pgedge.image",
+			Time:      fmt.Sprintf("%.3f", res.Duration.Seconds()),
+			SystemOut: strings.TrimSpace(res.Output),
+		}
+		if !res.Passed() {
+			suite.Failures++
+			tc.Failure = &junitFailure{
+				Message: res.Err.Error(),
+				Content: strings.TrimSpace(res.Output),
+			}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(r.Results)
+	suite.Time = fmt.Sprintf("%.3f", total.Seconds())
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return fmt.Errorf("error encoding junit report: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeTAP renders the report as TAP13.
+func (r *Report) writeTAP(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "TAP version 13\n1..%d\n", len(r.Results)); err != nil {
+		return err
+	}
+
+	for i, res := range r.Results {
+		status := "ok"
+		if !res.Passed() {
+			status = "not ok"
+		}
+		if _, err := fmt.Fprintf(w, "%s %d - %s\n", status, i+1, res.Name); err != nil {
+			return err
+		}
+		if res.Passed() {
+			continue
+		}
+
+		_, err := fmt.Fprintf(w, "  ---\n  message: %q\n  cmd: %q\n  exit_code: %d\n  duration_ms: %d\n  output: %q\n  ...\n",
+			res.Err.Error(), res.Cmd, res.ExitCode, res.Duration.Milliseconds(), strings.TrimSpace(res.Output))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed testsuites/default.yaml
+var defaultSuiteYAML []byte
+
+// expectSpec is the YAML representation of a Test's expected outcome.
+type expectSpec struct {
+	ExitCode       *int    `yaml:"exit_code"`
+	StdoutEquals   *string `yaml:"stdout_equals"`
+	StdoutContains string  `yaml:"stdout_contains"`
+	StdoutRegex    string  `yaml:"stdout_regex"`
+	StdoutPrefix   string  `yaml:"stdout_prefix"`
+}
+
+// suiteTest is the YAML representation of a Test.
+type suiteTest struct {
+	Name   string     `yaml:"name"`
+	Cmd    string     `yaml:"cmd"`
+	Flavor string     `yaml:"flavor"`
+	Serial bool       `yaml:"serial"`
+	Expect expectSpec `yaml:"expect"`
+}
+
+// testSuiteFile is the top-level shape of a -suite YAML file.
+type testSuiteFile struct {
+	Tests []suiteTest `yaml:"tests"`
+}
+
+// buildTestSuite assembles the full list of extension tests: the embedded
+// default suite that ships with this tool, plus any additional suites
+// named via -suite. Extra suites are appended, so existing extensions keep
+// working unchanged while new ones can be covered without a recompile.
+func buildTestSuite(suitePaths []string) ([]Test, error) {
+	tests, err := loadSuiteYAML(defaultSuiteYAML, "<embedded default suite>")
+	if err != nil {
+		return nil, fmt.Errorf("error loading default suite: %w", err)
+	}
+
+	for _, path := range suitePaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading suite %s: %w", path, err)
+		}
+
+		extra, err := loadSuiteYAML(data, path)
+		if err != nil {
+			return nil, fmt.Errorf("error loading suite %s: %w", path, err)
+		}
+		tests = append(tests, extra...)
+	}
+
+	return tests, nil
+}
+
+// parseSuitePaths splits a comma-separated -suite flag value.
+func parseSuitePaths(flagValue string) []string {
+	var paths []string
+	for _, p := range strings.Split(flagValue, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+func loadSuiteYAML(data []byte, source string) ([]Test, error) {
+	var file testSuiteFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", source, err)
+	}
+
+	tests := make([]Test, 0, len(file.Tests))
+	for _, st := range file.Tests {
+		expectFn, err := buildExpectFunc(st.Expect)
+		if err != nil {
+			return nil, fmt.Errorf("error in test %q (%s): %w", st.Name, source, err)
+		}
+
+		tests = append(tests, Test{
+			Name:           st.Name,
+			Cmd:            st.Cmd,
+			ExpectedOutput: expectFn,
+			StandardOnly:   st.Flavor == "standard",
+			Serial:         st.Serial,
+		})
+	}
+	return tests, nil
+}
+
+// buildExpectFunc compiles a YAML expect block into a Test.ExpectedOutput
+// closure. At most one stdout assertion is expected per test; if more than
+// one is set, stdout_equals takes precedence, then stdout_contains, then
+// stdout_prefix, then stdout_regex.
+func buildExpectFunc(e expectSpec) (func(int, string) error, error) {
+	wantExit := 0
+	if e.ExitCode != nil {
+		wantExit = *e.ExitCode
+	}
+
+	var stdoutRegex *regexp.Regexp
+	if e.StdoutRegex != "" {
+		re, err := regexp.Compile(e.StdoutRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stdout_regex: %w", err)
+		}
+		stdoutRegex = re
+	}
+
+	return func(exitCode int, output string) error {
+		if exitCode != wantExit {
+			return fmt.Errorf("unexpected exit code: %d", exitCode)
+		}
+
+		trimmed := strings.TrimSpace(output)
+		switch {
+		case e.StdoutEquals != nil:
+			if trimmed != *e.StdoutEquals {
+				return fmt.Errorf("unexpected output: %s", output)
+			}
+		case e.StdoutContains != "":
+			if !strings.Contains(output, e.StdoutContains) {
+				return fmt.Errorf("unexpected output: %s", output)
+			}
+		case e.StdoutPrefix != "":
+			if !strings.HasPrefix(trimmed, e.StdoutPrefix) {
+				return fmt.Errorf("unexpected output: %s", output)
+			}
+		case stdoutRegex != nil:
+			if !stdoutRegex.MatchString(output) {
+				return fmt.Errorf("unexpected output: %s", output)
+			}
+		}
+		return nil
+	}, nil
+}